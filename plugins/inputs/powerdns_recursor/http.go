@@ -0,0 +1,118 @@
+package powerdns_recursor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// statisticItem mirrors the shape of a single entry returned by the
+// Recursor's /api/v1/servers/localhost/statistics endpoint. Value is left
+// as json.RawMessage since its shape depends on Type: a plain number/string
+// for StatisticItem, an array of {name,value} pairs for MapStatisticItem
+// and RingStatisticItem.
+type statisticItem struct {
+	Name  string          `json:"name"`
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// mapStatisticEntry is the per-subnet/per-remote breakdown returned inside
+// the Value of a MapStatisticItem or RingStatisticItem.
+type mapStatisticEntry struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (p *PowerdnsRecursor) gatherHTTPServer(acc telegraf.Accumulator) error {
+	url := strings.TrimRight(p.URL, "/") + "/api/v1/servers/localhost/statistics"
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", p.APIKey)
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Timeout: defaultTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d from %q", resp.StatusCode, url)
+	}
+
+	var items []statisticItem
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return fmt.Errorf("error decoding response from %q: %v", url, err)
+	}
+
+	for _, item := range items {
+		p.addStatisticItem(acc, item)
+	}
+
+	return nil
+}
+
+func (p *PowerdnsRecursor) addStatisticItem(acc telegraf.Accumulator, item statisticItem) {
+	switch item.Type {
+	case "StatisticItem":
+		var raw string
+		if err := json.Unmarshal(item.Value, &raw); err != nil {
+			// Some StatisticItem values are encoded as a bare number rather
+			// than a string.
+			raw = string(item.Value)
+		}
+
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			log.Printf("E! [inputs.powerdns_recursor] error parsing integer for metric %q: %s",
+				item.Name, err.Error())
+			return
+		}
+
+		acc.AddFields("powerdns_recursor", map[string]interface{}{item.Name: value}, map[string]string{"server": p.URL})
+	case "MapStatisticItem":
+		p.addMapStatisticItem(acc, item, mapTagName(item.Name))
+	case "RingStatisticItem":
+		p.addMapStatisticItem(acc, item, "ring")
+	}
+}
+
+func (p *PowerdnsRecursor) addMapStatisticItem(acc telegraf.Accumulator, item statisticItem, tagName string) {
+	var entries []mapStatisticEntry
+	if err := json.Unmarshal(item.Value, &entries); err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		value, err := strconv.ParseInt(entry.Value, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		tags := map[string]string{"server": p.URL, tagName: entry.Name}
+		acc.AddFields("powerdns_recursor", map[string]interface{}{item.Name: value}, tags)
+	}
+}
+
+// mapTagName picks a tag key for a MapStatisticItem based on its counter
+// name, e.g. "remote-errors-by-range" is broken down by remote.
+func mapTagName(name string) string {
+	switch {
+	case strings.Contains(name, "subnet"):
+		return "subnet"
+	case strings.Contains(name, "remote"):
+		return "remote"
+	default:
+		return "item"
+	}
+}
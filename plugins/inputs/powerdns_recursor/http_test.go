@@ -0,0 +1,82 @@
+package powerdns_recursor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddStatisticItemStatisticItem(t *testing.T) {
+	p := &PowerdnsRecursor{URL: "http://127.0.0.1:8082"}
+	acc := &testutil.Accumulator{}
+
+	p.addStatisticItem(acc, statisticItem{
+		Name:  "questions",
+		Type:  "StatisticItem",
+		Value: json.RawMessage(`"12345"`),
+	})
+
+	acc.AssertContainsTaggedFields(t, "powerdns_recursor",
+		map[string]interface{}{"questions": int64(12345)},
+		map[string]string{"server": p.URL})
+}
+
+func TestAddStatisticItemStatisticItemBareNumber(t *testing.T) {
+	p := &PowerdnsRecursor{URL: "http://127.0.0.1:8082"}
+	acc := &testutil.Accumulator{}
+
+	p.addStatisticItem(acc, statisticItem{
+		Name:  "questions",
+		Type:  "StatisticItem",
+		Value: json.RawMessage(`12345`),
+	})
+
+	acc.AssertContainsTaggedFields(t, "powerdns_recursor",
+		map[string]interface{}{"questions": int64(12345)},
+		map[string]string{"server": p.URL})
+}
+
+func TestAddStatisticItemStatisticItemUnparsableIsDropped(t *testing.T) {
+	p := &PowerdnsRecursor{URL: "http://127.0.0.1:8082"}
+	acc := &testutil.Accumulator{}
+
+	p.addStatisticItem(acc, statisticItem{
+		Name:  "some-float-counter",
+		Type:  "StatisticItem",
+		Value: json.RawMessage(`"12.5"`),
+	})
+
+	require.Equal(t, 0, acc.NFields())
+}
+
+func TestAddStatisticItemMapStatisticItem(t *testing.T) {
+	p := &PowerdnsRecursor{URL: "http://127.0.0.1:8082"}
+	acc := &testutil.Accumulator{}
+
+	p.addStatisticItem(acc, statisticItem{
+		Name:  "remote-errors-by-range",
+		Type:  "MapStatisticItem",
+		Value: json.RawMessage(`[{"name":"127.0.0.1","value":"7"}]`),
+	})
+
+	acc.AssertContainsTaggedFields(t, "powerdns_recursor",
+		map[string]interface{}{"remote-errors-by-range": int64(7)},
+		map[string]string{"server": p.URL, "remote": "127.0.0.1"})
+}
+
+func TestAddStatisticItemRingStatisticItem(t *testing.T) {
+	p := &PowerdnsRecursor{URL: "http://127.0.0.1:8082"}
+	acc := &testutil.Accumulator{}
+
+	p.addStatisticItem(acc, statisticItem{
+		Name:  "top-queries",
+		Type:  "RingStatisticItem",
+		Value: json.RawMessage(`[{"name":"example.com","value":"3"}]`),
+	})
+
+	acc.AssertContainsTaggedFields(t, "powerdns_recursor",
+		map[string]interface{}{"top-queries": int64(3)},
+		map[string]string{"server": p.URL, "ring": "example.com"})
+}
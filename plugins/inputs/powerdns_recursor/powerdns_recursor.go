@@ -3,11 +3,7 @@ package powerdns_recursor
 import (
 	"errors"
 	"fmt"
-	"log"
-	"math/rand"
-	"net"
-	"os"
-	"path/filepath"
+	"os/user"
 	"strconv"
 	"strings"
 	"time"
@@ -20,9 +16,18 @@ type PowerdnsRecursor struct {
 	UnixSockets        []string `toml:"unix_sockets"`
 	SocketDir          string   `toml:"socket_dir"`
 	SocketMode         string   `toml:"socket_mode"`
-	NewControlProtocol bool     `toml:"new_control_protocol"`
+	SocketOwner        string   `toml:"socket_owner"`
+	SocketPathTemplate string   `toml:"socket_path_template"`
+	ControlProtocol    string   `toml:"control_protocol"`
 
-	mode uint32
+	URL    string `toml:"url"`
+	APIKey string `toml:"api_key"`
+
+	Metrics   []string `toml:"metrics"`
+	PerThread bool     `toml:"per_thread"`
+
+	mode     uint32
+	uid, gid int
 }
 
 var defaultTimeout = 5 * time.Second
@@ -38,8 +43,34 @@ var sampleConfig = `
   ## Socket permissions for the receive socket.
   # socket_mode = "0666"
 
-  ## IMPORTANT: Set this to true if you're running PowerDNS 4.5.0 or newer.
-  # new_control_protocol = false
+  ## Owner of the receive socket, as "user" or "user:group". Set this if
+  ## the recursor runs as a non-root user and needs write access to reply.
+  # socket_owner = "pdns:pdns"
+
+  ## Filename template for the receive socket, relative to socket_dir. A
+  ## "%d" verb, if present, is replaced with a random number; without one
+  ## the path is fixed, which is useful to tell apart the sockets of
+  ## multiple telegraf instances polling the same recursor.
+  # socket_path_template = "pdns_recursor_telegraf%d"
+
+  ## Which control-socket protocol to speak. "v1" is the original
+  ## unixgram protocol, "v3" is the stream-based protocol used by
+  ## Recursor 4.6.0+. "auto" tries v3 first and falls back to v1.
+  # control_protocol = "auto"
+
+  ## Alternatively, metrics can be collected from the Recursor's built-in
+  ## webserver (PowerDNS Recursor 4.1+). This is mutually exclusive with
+  ## unix_sockets and is useful for monitoring remote recursors.
+  # url = "http://127.0.0.1:8082"
+  # api_key = "changeme"
+
+  ## Only retrieve these counters instead of the full counter set. Leave
+  ## empty to fetch everything ("get-all").
+  # metrics = ["questions", "cache-hits", "cache-misses"]
+
+  ## Also report a per-worker-thread breakdown of the requested counters,
+  ## tagged with "thread".
+  # per_thread = false
 `
 
 func (p *PowerdnsRecursor) SampleConfig() string {
@@ -51,6 +82,16 @@ func (p *PowerdnsRecursor) Description() string {
 }
 
 func (p *PowerdnsRecursor) Init() error {
+	if p.URL != "" && len(p.UnixSockets) != 0 {
+		return errors.New("url and unix_sockets are mutually exclusive")
+	}
+
+	switch p.ControlProtocol {
+	case "", "auto", "v1", "v3":
+	default:
+		return fmt.Errorf("invalid control_protocol %q: must be one of \"auto\", \"v1\" or \"v3\"", p.ControlProtocol)
+	}
+
 	if p.SocketMode != "" {
 		mode, err := strconv.ParseUint(p.SocketMode, 8, 32)
 		if err != nil {
@@ -59,16 +100,48 @@ func (p *PowerdnsRecursor) Init() error {
 
 		p.mode = uint32(mode)
 	}
+
+	p.uid, p.gid = -1, -1
+	if p.SocketOwner != "" {
+		userName, groupName, _ := strings.Cut(p.SocketOwner, ":")
+
+		u, err := user.Lookup(userName)
+		if err != nil {
+			return fmt.Errorf("could not look up socket_owner user %q: %v", userName, err)
+		}
+		if p.uid, err = strconv.Atoi(u.Uid); err != nil {
+			return fmt.Errorf("could not parse uid for socket_owner user %q: %v", userName, err)
+		}
+
+		if groupName == "" {
+			if p.gid, err = strconv.Atoi(u.Gid); err != nil {
+				return fmt.Errorf("could not parse gid for socket_owner user %q: %v", userName, err)
+			}
+		} else {
+			g, err := user.LookupGroup(groupName)
+			if err != nil {
+				return fmt.Errorf("could not look up socket_owner group %q: %v", groupName, err)
+			}
+			if p.gid, err = strconv.Atoi(g.Gid); err != nil {
+				return fmt.Errorf("could not parse gid for socket_owner group %q: %v", groupName, err)
+			}
+		}
+	}
+
 	return nil
 }
 
 func (p *PowerdnsRecursor) Gather(acc telegraf.Accumulator) error {
+	if p.URL != "" {
+		return p.gatherHTTPServer(acc)
+	}
+
 	if len(p.UnixSockets) == 0 {
-		return p.gatherServer("/var/run/pdns_recursor.controlsocket", p.NewControlProtocol, acc)
+		return p.gatherServer("/var/run/pdns_recursor.controlsocket", acc)
 	}
 
 	for _, serverSocket := range p.UnixSockets {
-		if err := p.gatherServer(serverSocket, p.NewControlProtocol, acc); err != nil {
+		if err := p.gatherServer(serverSocket, acc); err != nil {
 			acc.AddError(err)
 		}
 	}
@@ -76,117 +149,67 @@ func (p *PowerdnsRecursor) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
-func (p *PowerdnsRecursor) gatherServer(address string, newProtocol bool, acc telegraf.Accumulator) error {
-	randomNumber := rand.Int63()
-	recvSocket := filepath.Join("/", "var", "run", fmt.Sprintf("pdns_recursor_telegraf%d", randomNumber))
-	if p.SocketDir != "" {
-		recvSocket = filepath.Join(p.SocketDir, fmt.Sprintf("pdns_recursor_telegraf%d", randomNumber))
+func (p *PowerdnsRecursor) gatherServer(address string, acc telegraf.Accumulator) error {
+	recvOpts := recvSocketOptions{
+		Dir:          p.SocketDir,
+		PathTemplate: p.SocketPathTemplate,
+		Mode:         p.mode,
+		UID:          p.uid,
+		GID:          p.gid,
 	}
 
-	laddr, err := net.ResolveUnixAddr("unixgram", recvSocket)
-
+	proto, err := newRecursorProto(p.ControlProtocol, address, recvOpts)
 	if err != nil {
 		return err
 	}
+	defer proto.Close()
 
-	defer os.Remove(recvSocket)
-
-	raddr, err := net.ResolveUnixAddr("unixgram", address)
-
-	if err != nil {
-		return err
-	}
-	conn, err := net.DialUnix("unixgram", laddr, raddr)
+	cmd, args := p.buildCommand(false)
+	data, err := proto.Request(cmd, args...)
 	if err != nil {
 		return err
 	}
-	if err := os.Chmod(recvSocket, os.FileMode(p.mode)); err != nil {
-		return err
-	}
-	defer conn.Close()
 
-	if err := conn.SetDeadline(time.Now().Add(defaultTimeout)); err != nil {
-		return err
-	}
+	// Add server socket as a tag
+	tags := map[string]string{"server": address}
 
-	if newProtocol {
-		// First send a 0 status code.
-		_, err = conn.Write([]byte{0, 0, 0, 0})
+	acc.AddFields("powerdns_recursor", parseResponse(string(data)), tags)
 
+	if p.PerThread {
+		cmd, args := p.buildCommand(true)
+		threadedData, err := proto.Request(cmd, args...)
 		if err != nil {
 			return err
 		}
-	}
-
-	// Then send the get-all command.
-	command := "get-all\n"
-
-	if newProtocol {
-		command = "get-all"
-	}
 
-	_, err = conn.Write([]byte(command))
-
-	if err != nil {
-		return err
-	}
-
-	if newProtocol {
-		// Read the response status code.
-		status := make([]byte, 4)
-		n, err := conn.Read(status)
-		if err != nil {
-			return err
-		}
-		if n == 0 {
-			return errors.New("no status code received")
+		for thread, fields := range parseThreadedResponse(string(threadedData)) {
+			threadTags := map[string]string{"server": address, "thread": strconv.Itoa(thread)}
+			acc.AddFields("powerdns_recursor", fields, threadTags)
 		}
 	}
 
-	// Read the response data.
-	buf := make([]byte, 16384)
-	n, err := conn.Read(buf)
-	if err != nil {
-		return err
-	}
-	if n == 0 {
-		return errors.New("no data received")
-	}
-
-	metrics := string(buf)
-
-	// Process data
-	fields := parseResponse(metrics)
-
-	// Add server socket as a tag
-	tags := map[string]string{"server": address}
-
-	acc.AddFields("powerdns_recursor", fields, tags)
-
-	return conn.Close()
+	return nil
 }
 
-func parseResponse(metrics string) map[string]interface{} {
-	values := make(map[string]interface{})
-
-	s := strings.Split(metrics, "\n")
-
-	for _, metric := range s[:len(s)-1] {
-		m := strings.Split(metric, "\t")
-		if len(m) < 2 {
-			continue
+// buildCommand picks the control-socket command for the configured metric
+// selection. An empty Metrics list means "everything" (get-all), otherwise
+// only the named counters are requested (get NAME [NAME...]). The threaded
+// variant of each command returns a per-worker-thread breakdown instead of
+// the aggregated counters.
+func (p *PowerdnsRecursor) buildCommand(threaded bool) (string, []string) {
+	if len(p.Metrics) == 0 {
+		if threaded {
+			return "get-all-threaded", nil
 		}
+		return "get-all", nil
+	}
 
-		i, err := strconv.ParseInt(m[1], 10, 64)
-		if err != nil {
-			log.Printf("E! [inputs.powerdns_recursor] error parsing integer for metric %q: %s",
-				metric, err.Error())
-			continue
-		}
-		values[m[0]] = i
+	cmd := "get"
+	if threaded {
+		cmd = "get-threaded"
 	}
 
-	return values
+	return cmd, p.Metrics
 }
 
 func init() {
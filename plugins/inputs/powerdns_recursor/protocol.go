@@ -0,0 +1,51 @@
+package powerdns_recursor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+)
+
+// recursorProto abstracts the two control-socket wire formats spoken by
+// different PowerDNS Recursor versions, so that gatherServer doesn't need
+// to know which one it's talking to.
+type recursorProto interface {
+	// Request sends cmd, optionally followed by args (e.g. counter names
+	// for "get"), and returns the raw response payload.
+	Request(cmd string, args ...string) ([]byte, error)
+	Close() error
+}
+
+// newRecursorProto opens a connection to address using the protocol
+// selected by controlProtocol ("auto", "v1" or "v3"). In "auto" mode, V3
+// (the modern stream-based protocol, available since Recursor 4.6.0) is
+// tried first; if dialing it fails the way it would against a V1
+// (unixgram) control socket, V1 is used instead.
+func newRecursorProto(controlProtocol, address string, recvOpts recvSocketOptions) (recursorProto, error) {
+	switch controlProtocol {
+	case "v1":
+		return newProtoV1(address, recvOpts)
+	case "v3":
+		return newProtoV3(address)
+	case "", "auto":
+		proto, err := newProtoV3(address)
+		if err == nil {
+			return proto, nil
+		}
+		if !isAutoFallbackError(err) {
+			return nil, err
+		}
+		return newProtoV1(address, recvOpts)
+	default:
+		return nil, fmt.Errorf("invalid control_protocol %q: must be one of \"auto\", \"v1\" or \"v3\"", controlProtocol)
+	}
+}
+
+// isAutoFallbackError reports whether err looks like address is actually a
+// V1 (unixgram) control socket rather than a V3 (stream) one, in which
+// case auto-detection should retry with the V1 protocol. A stream connect
+// to a unixgram socket fails with EPROTOTYPE.
+func isAutoFallbackError(err error) bool {
+	return errors.Is(err, syscall.EPROTOTYPE) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
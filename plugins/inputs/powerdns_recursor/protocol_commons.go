@@ -3,12 +3,17 @@ package powerdns_recursor
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
+	"log"
 	"net"
 	"strconv"
 	"strings"
 	"unsafe"
 )
 
+// parseResponse turns a get-all/get reply ("name\tvalue" rows, one per
+// line) into a telegraf field map. It is shared by every recursorProto
+// implementation.
 func parseResponse(metrics string) map[string]interface{} {
 	values := make(map[string]interface{})
 
@@ -22,6 +27,8 @@ func parseResponse(metrics string) map[string]interface{} {
 
 		i, err := strconv.ParseInt(m[1], 10, 64)
 		if err != nil {
+			log.Printf("E! [inputs.powerdns_recursor] error parsing integer for metric %q: %s",
+				metric, err.Error())
 			continue
 		}
 
@@ -31,6 +38,53 @@ func parseResponse(metrics string) map[string]interface{} {
 	return values
 }
 
+// threadFieldPrefix is applied to per-thread counter names so that, should
+// they ever end up alongside the global counters on the same measurement,
+// they don't collide with the non-threaded field of the same name.
+const threadFieldPrefix = "thread_"
+
+// parseThreadedResponse parses the reply to a threaded ("-threaded") get
+// command. Unlike the flat get-all/get reply, it carries a bare
+// "thread=N" marker line ahead of each worker's block of "name\tvalue"
+// rows.
+func parseThreadedResponse(metrics string) map[int]map[string]interface{} {
+	result := make(map[int]map[string]interface{})
+	thread := -1
+
+	for _, line := range strings.Split(metrics, "\n") {
+		if strings.HasPrefix(line, "thread=") {
+			n, err := strconv.Atoi(strings.TrimPrefix(line, "thread="))
+			if err != nil {
+				continue
+			}
+
+			thread = n
+			if _, ok := result[thread]; !ok {
+				result[thread] = make(map[string]interface{})
+			}
+			continue
+		}
+
+		if thread < 0 {
+			continue
+		}
+
+		m := strings.SplitN(line, "\t", 2)
+		if len(m) < 2 {
+			continue
+		}
+
+		i, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		result[thread][threadFieldPrefix+m[0]] = i
+	}
+
+	return result
+}
+
 // This below is generally unsafe but necessary in this case
 // since the powerdns protocol encoding is host dependent.
 // The C implementation uses size_t as the size type for the
@@ -74,16 +128,10 @@ func writeNativeUIntToConn(conn net.Conn, value uint) (int, error) {
 func readNativeUIntFromConn(conn net.Conn) (uint, error) {
 	intData := make([]byte, uintSizeInBytes)
 
-	n, err := conn.Read(intData)
-
-	if err != nil {
+	if _, err := io.ReadFull(conn, intData); err != nil {
 		return 0, err
 	}
 
-	if n != uintSizeInBytes {
-		return 0, fmt.Errorf("did not read enough data for native uint: read '%v' bytes, expected '%v'", n, uintSizeInBytes)
-	}
-
 	if uintSizeInBytes == 4 {
 		return uint(getEndianness().Uint32(intData)), nil
 	} else if uintSizeInBytes == 8 {
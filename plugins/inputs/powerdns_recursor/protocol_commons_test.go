@@ -0,0 +1,39 @@
+package powerdns_recursor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResponse(t *testing.T) {
+	values := parseResponse("questions\t100\ncache-hits\t42\nuptime\tnotanumber\n")
+
+	require.Equal(t, map[string]interface{}{
+		"questions":  int64(100),
+		"cache-hits": int64(42),
+	}, values)
+}
+
+func TestParseResponseEmpty(t *testing.T) {
+	require.Empty(t, parseResponse(""))
+}
+
+func TestParseThreadedResponse(t *testing.T) {
+	reply := "thread=0\nquestions\t10\ncache-hits\t1\nthread=1\nquestions\t20\ncache-hits\t2\n"
+
+	values := parseThreadedResponse(reply)
+
+	require.Equal(t, map[int]map[string]interface{}{
+		0: {"thread_questions": int64(10), "thread_cache-hits": int64(1)},
+		1: {"thread_questions": int64(20), "thread_cache-hits": int64(2)},
+	}, values)
+}
+
+func TestParseThreadedResponseIgnoresRowsBeforeFirstMarker(t *testing.T) {
+	values := parseThreadedResponse("questions\t10\nthread=0\nquestions\t20\n")
+
+	require.Equal(t, map[int]map[string]interface{}{
+		0: {"thread_questions": int64(20)},
+	}, values)
+}
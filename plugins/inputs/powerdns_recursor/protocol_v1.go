@@ -0,0 +1,192 @@
+package powerdns_recursor
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// drainInterval bounds how long protoV1 waits for a further datagram once
+// it has already received at least one, since a large reply can be split
+// by the recursor across more than one datagram.
+const drainInterval = 200 * time.Millisecond
+
+// protoV1 speaks the original PowerDNS Recursor control protocol: a
+// connected unixgram socket carrying one newline-terminated command and a
+// datagram-oriented response.
+type protoV1 struct {
+	conn       *net.UnixConn
+	recvSocket string
+}
+
+// recvSocketOptions configures the ephemeral unixgram socket protoV1 binds
+// to in order to receive the recursor's reply.
+type recvSocketOptions struct {
+	// Dir is the directory the receive socket is created in. Defaults to
+	// /var/run.
+	Dir string
+	// PathTemplate, if set, overrides the "pdns_recursor_telegraf%d"
+	// filename. A "%d" verb, if present, is replaced with a random
+	// number; a template without one yields a fixed, predictable path,
+	// which is useful to keep multiple telegraf instances from racing
+	// for the same default name.
+	PathTemplate string
+	// Mode is the filesystem permissions applied to the receive socket.
+	Mode uint32
+	// UID and GID chown the receive socket so that a recursor running as
+	// an unprivileged, non-root user can still write its reply to it.
+	// A value of -1 leaves the corresponding ownership unchanged.
+	UID int
+	GID int
+}
+
+func newProtoV1(address string, opts recvSocketOptions) (*protoV1, error) {
+	recvSocket := buildRecvSocketPath(opts.Dir, opts.PathTemplate)
+
+	if err := removeStaleSocket(recvSocket); err != nil {
+		return nil, err
+	}
+
+	laddr, err := net.ResolveUnixAddr("unixgram", recvSocket)
+	if err != nil {
+		return nil, err
+	}
+
+	raddr, err := net.ResolveUnixAddr("unixgram", address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialUnix("unixgram", laddr, raddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(recvSocket, os.FileMode(opts.Mode)); err != nil {
+		conn.Close()
+		os.Remove(recvSocket)
+		return nil, err
+	}
+
+	if opts.UID >= 0 || opts.GID >= 0 {
+		if err := os.Chown(recvSocket, opts.UID, opts.GID); err != nil {
+			conn.Close()
+			os.Remove(recvSocket)
+			return nil, err
+		}
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(defaultTimeout)); err != nil {
+		conn.Close()
+		os.Remove(recvSocket)
+		return nil, err
+	}
+
+	return &protoV1{conn: conn, recvSocket: recvSocket}, nil
+}
+
+// buildRecvSocketPath resolves the directory and filename of the
+// ephemeral receive socket. template may contain a "%d" verb that gets
+// replaced by a random number; without one, the returned path is fixed.
+func buildRecvSocketPath(dir, template string) string {
+	name := template
+	switch {
+	case name == "":
+		name = fmt.Sprintf("pdns_recursor_telegraf%d", rand.Int63())
+	case strings.Contains(name, "%d"):
+		name = fmt.Sprintf(name, rand.Int63())
+	}
+
+	if dir == "" {
+		dir = filepath.Join("/", "var", "run")
+	}
+
+	return filepath.Join(dir, name)
+}
+
+// removeStaleSocket clears a leftover receive socket from a previous,
+// presumably crashed, run. It refuses to remove anything that isn't
+// actually a socket, so a misconfigured path or genuine file collision
+// fails loudly instead of silently deleting user data.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("refusing to remove %q left over from a previous run: not a socket", path)
+	}
+
+	return os.Remove(path)
+}
+
+func (v *protoV1) Request(cmd string, args ...string) ([]byte, error) {
+	command := cmd
+	if len(args) > 0 {
+		command = cmd + " " + strings.Join(args, " ")
+	}
+
+	if _, err := v.conn.Write([]byte(command + "\n")); err != nil {
+		return nil, err
+	}
+
+	return v.drainResponse()
+}
+
+// drainResponse keeps reading datagrams off the receive socket until none
+// arrives within drainInterval of the previous one, rather than assuming
+// the entire reply arrives in a single datagram. The first datagram is
+// waited for up to defaultTimeout. Each individual datagram is read in
+// full via recvDatagram (on Windows, up to maxDatagramSize bytes).
+func (v *protoV1) drainResponse() ([]byte, error) {
+	var result []byte
+
+	for first := true; ; first = false {
+		timeout := drainInterval
+		if first {
+			timeout = defaultTimeout
+		}
+		if err := v.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, err
+		}
+
+		chunk, err := recvDatagram(v.conn)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			if len(result) > 0 {
+				break
+			}
+			return nil, err
+		}
+
+		if len(chunk) == 0 {
+			break
+		}
+
+		result = append(result, chunk...)
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New("no data received")
+	}
+
+	return result, nil
+}
+
+func (v *protoV1) Close() error {
+	err := v.conn.Close()
+	os.Remove(v.recvSocket)
+	return err
+}
@@ -0,0 +1,63 @@
+package powerdns_recursor
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildRecvSocketPathDefault(t *testing.T) {
+	path := buildRecvSocketPath("", "")
+
+	require.Equal(t, filepath.Join("/", "var", "run"), filepath.Dir(path))
+	require.Regexp(t, `^pdns_recursor_telegraf\d+$`, filepath.Base(path))
+}
+
+func TestBuildRecvSocketPathFixedTemplate(t *testing.T) {
+	path := buildRecvSocketPath("/tmp", "pdns_recursor_telegraf_instance1")
+
+	require.Equal(t, "/tmp/pdns_recursor_telegraf_instance1", path)
+}
+
+func TestBuildRecvSocketPathTemplateWithVerb(t *testing.T) {
+	path := buildRecvSocketPath("/tmp", "pdns_recursor_telegraf_instance1_%d")
+
+	require.Regexp(t, `^/tmp/pdns_recursor_telegraf_instance1_\d+$`, path)
+}
+
+func TestRemoveStaleSocketMissingIsNoop(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, removeStaleSocket(filepath.Join(dir, "does-not-exist")))
+}
+
+func TestRemoveStaleSocketRemovesSocket(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stale.sock")
+
+	laddr, err := net.ResolveUnixAddr("unixgram", path)
+	require.NoError(t, err)
+	conn, err := net.ListenUnixgram("unixgram", laddr)
+	require.NoError(t, err)
+	conn.Close()
+
+	require.NoError(t, removeStaleSocket(path))
+	_, err = os.Lstat(path)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestRemoveStaleSocketRefusesNonSocket(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "not-a-socket")
+
+	require.NoError(t, os.WriteFile(path, []byte("not a socket"), 0o644))
+
+	err := removeStaleSocket(path)
+	require.Error(t, err)
+
+	_, statErr := os.Lstat(path)
+	require.NoError(t, statErr, "file must not be removed")
+}
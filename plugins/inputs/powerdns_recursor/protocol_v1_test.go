@@ -0,0 +1,69 @@
+//go:build !windows
+
+package powerdns_recursor
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestRecvDatagramDoesNotTruncate reproduces the scenario from the
+// original bug report: a single datagram larger than the 16 KiB read
+// buffer used to silently truncate. recvDatagram must return it whole.
+func TestRecvDatagramDoesNotTruncate(t *testing.T) {
+	dir := t.TempDir()
+	serverAddr := filepath.Join(dir, "server.sock")
+	clientAddr := filepath.Join(dir, "client.sock")
+
+	serverLaddr, err := net.ResolveUnixAddr("unixgram", serverAddr)
+	require.NoError(t, err)
+	server, err := net.ListenUnixgram("unixgram", serverLaddr)
+	require.NoError(t, err)
+	defer server.Close()
+
+	clientLaddr, err := net.ResolveUnixAddr("unixgram", clientAddr)
+	require.NoError(t, err)
+	client, err := net.DialUnix("unixgram", clientLaddr, serverLaddr)
+	require.NoError(t, err)
+	defer client.Close()
+
+	big := make([]byte, 50000)
+	for i := range big {
+		big[i] = 'a'
+	}
+
+	_, err = server.WriteTo(big, clientLaddr)
+	require.NoError(t, err)
+
+	got, err := recvDatagram(client)
+	require.NoError(t, err)
+	require.Equal(t, big, got)
+}
+
+func TestRecvDatagramSmallMessage(t *testing.T) {
+	dir := t.TempDir()
+	serverAddr := filepath.Join(dir, "server.sock")
+	clientAddr := filepath.Join(dir, "client.sock")
+
+	serverLaddr, err := net.ResolveUnixAddr("unixgram", serverAddr)
+	require.NoError(t, err)
+	server, err := net.ListenUnixgram("unixgram", serverLaddr)
+	require.NoError(t, err)
+	defer server.Close()
+
+	clientLaddr, err := net.ResolveUnixAddr("unixgram", clientAddr)
+	require.NoError(t, err)
+	client, err := net.DialUnix("unixgram", clientLaddr, serverLaddr)
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = server.WriteTo([]byte("questions\t10\n"), clientLaddr)
+	require.NoError(t, err)
+
+	got, err := recvDatagram(client)
+	require.NoError(t, err)
+	require.Equal(t, []byte("questions\t10\n"), got)
+}
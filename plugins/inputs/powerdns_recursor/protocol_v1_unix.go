@@ -0,0 +1,50 @@
+//go:build !windows
+
+package powerdns_recursor
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// recvDatagram reads one full unixgram datagram off conn, regardless of
+// its size. A naive conn.Read into a fixed-size buffer silently truncates
+// once a reply (e.g. a get-all response on a recursor with many counters)
+// exceeds that buffer, since datagram sockets discard whatever didn't
+// fit. To avoid that, MSG_PEEK|MSG_TRUNC is used first: on Linux, passing
+// MSG_TRUNC makes the kernel report the datagram's real length even
+// though the peek buffer is empty, which lets us size the actual read
+// exactly instead of guessing.
+func recvDatagram(conn *net.UnixConn) ([]byte, error) {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var msgLen int
+	var peekErr error
+
+	if ctrlErr := rawConn.Read(func(fd uintptr) bool {
+		msgLen, _, _, _, peekErr = unix.Recvmsg(int(fd), nil, nil, unix.MSG_PEEK|unix.MSG_TRUNC)
+		// Nothing queued yet: returning false makes the runtime wait for
+		// the socket to become readable, bounded by the read deadline.
+		return peekErr != unix.EAGAIN
+	}); ctrlErr != nil {
+		return nil, ctrlErr
+	}
+	if peekErr != nil {
+		return nil, peekErr
+	}
+	if msgLen == 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, msgLen)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf[:n], nil
+}
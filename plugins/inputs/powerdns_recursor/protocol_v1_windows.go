@@ -0,0 +1,22 @@
+//go:build windows
+
+package powerdns_recursor
+
+import "net"
+
+// maxDatagramSize bounds the single-shot read used here, since Windows
+// has no MSG_TRUNC equivalent exposed for us to size the read exactly.
+// In practice this path isn't exercised: PowerDNS Recursor's control
+// socket is unix-domain only, so this build just needs to compile.
+const maxDatagramSize = 1 << 20 // 1 MiB
+
+func recvDatagram(conn *net.UnixConn) ([]byte, error) {
+	buf := make([]byte, maxDatagramSize)
+
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf[:n], nil
+}
@@ -1,81 +1,92 @@
 package powerdns_recursor
 
 import (
+	"errors"
 	"fmt"
-	"github.com/influxdata/telegraf"
+	"io"
 	"net"
+	"strings"
 	"time"
 )
 
-// V3 (4.6.0+) Protocol:
-// Standard unix stream socket
-// Synchronous request / response
-// Data structure:
-// status: uint32
-// dataLength: size_t
-// data: byte[dataLength]
-func (p *PowerdnsRecursor) gatherFromV3Server(address string, acc telegraf.Accumulator) error {
+// protoV3 speaks the PowerDNS Recursor 4.6.0+ control protocol: a
+// standard unix stream socket with a synchronous, length-framed
+// request/response.
+//
+// Request:
+//
+//	status: uint32 (always 0)
+//	cmdLength: size_t
+//	cmd: byte[cmdLength]
+//
+// Response:
+//
+//	status: uint32
+//	dataLength: size_t
+//	data: byte[dataLength]
+type protoV3 struct {
+	conn net.Conn
+}
+
+func newProtoV3(address string) (*protoV3, error) {
 	conn, err := net.Dial("unix", address)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	defer conn.Close()
-
 	if err := conn.SetDeadline(time.Now().Add(defaultTimeout)); err != nil {
-		return err
+		conn.Close()
+		return nil, err
+	}
+
+	return &protoV3{conn: conn}, nil
+}
+
+func (v *protoV3) Request(cmd string, args ...string) ([]byte, error) {
+	command := cmd
+	if len(args) > 0 {
+		command = cmd + " " + strings.Join(args, " ")
 	}
 
 	// Write 4-byte response code.
-	if _, err = conn.Write([]byte{0, 0, 0, 0}); err != nil {
-		return err
+	if _, err := v.conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return nil, err
 	}
 
-	command := []byte("get-all")
+	payload := []byte(command)
 
-	if _, err = writeNativeUIntToConn(conn, uint(len(command))); err != nil {
-		return err
+	if _, err := writeNativeUIntToConn(v.conn, uint(len(payload))); err != nil {
+		return nil, err
 	}
 
-	if _, err = conn.Write(command); err != nil {
-		return err
+	if _, err := v.conn.Write(payload); err != nil {
+		return nil, err
 	}
 
-	// Now read the response.
+	// Now read the response. io.ReadFull is required here: net.Conn.Read
+	// is allowed to return less than len(buf) even when more data is on
+	// the way, so a single Read cannot be trusted to fill the buffer.
 	status := make([]byte, 4)
-	n, err := conn.Read(status)
-	if err != nil {
-		return err
-	}
-	if n == 0 {
-		return fmt.Errorf("no status code received")
+	if _, err := io.ReadFull(v.conn, status); err != nil {
+		return nil, fmt.Errorf("error reading status code: %w", err)
 	}
 
-	responseLength, err := readNativeUIntFromConn(conn)
+	responseLength, err := readNativeUIntFromConn(v.conn)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if responseLength == 0 {
-		return fmt.Errorf("received data length was 0")
+		return nil, errors.New("received data length was 0")
 	}
 
 	data := make([]byte, responseLength)
-	n, err = conn.Read(data)
-	if err != nil {
-		return err
-	}
-	if uint(n) != responseLength {
-		return fmt.Errorf("no data received, expected '%v' bytes but got '%v'", responseLength, n)
+	if _, err := io.ReadFull(v.conn, data); err != nil {
+		return nil, fmt.Errorf("error reading response data, expected %d bytes: %w", responseLength, err)
 	}
 
-	// Process data
-	metrics := string(data)
-	fields := parseResponse(metrics)
-
-	// Add server socket as a tag
-	tags := map[string]string{"server": address}
-
-	acc.AddFields("powerdns_recursor", fields, tags)
+	return data, nil
+}
 
-	return conn.Close()
+func (v *protoV3) Close() error {
+	return v.conn.Close()
 }